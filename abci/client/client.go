@@ -18,30 +18,37 @@ const (
 //go:generate mockery --case underscore --name Client
 
 // Client defines an interface for an ABCI client.
-// All `Async` methods return a `ReqRes` object.
+// All `Async` methods return a `ReqRes` object and queue the request without
+// blocking on the result; submission-time errors (eg. the connection having
+// died) are stashed on the returned ReqRes and surfaced through its Err()
+// method rather than returned directly.
 // All `Sync` methods return the appropriate protobuf ResponseXxx struct and an error.
 // Note these are client errors, eg. ABCI socket connectivity issues.
 // Application-related errors are reflected in response via ABCI error codes and logs.
 type Client interface {
 	service.Service
 
-	SetResponseCallback(Callback)
+	// SetGlobalCallback installs a callback invoked for every request/response
+	// pair, ahead of any per-ReqRes callback set via ReqRes.SetCallback.
+	// NOTE: not yet enforced by the socket/gRPC clients, which aren't in this tree.
+	SetGlobalCallback(GlobalCallback)
+	GetGlobalCallback() GlobalCallback
 	Error() error
 
-	FlushAsync() (*ReqRes, error)
-	EchoAsync(msg string) (*ReqRes, error)
-	InfoAsync(types.RequestInfo) (*ReqRes, error)
-	DeliverTxAsync(types.RequestDeliverTx) (*ReqRes, error)
-	CheckTxAsync(types.RequestCheckTx) (*ReqRes, error)
-	QueryAsync(types.RequestQuery) (*ReqRes, error)
-	CommitAsync() (*ReqRes, error)
-	InitChainAsync(types.RequestInitChain) (*ReqRes, error)
-	BeginBlockAsync(types.RequestBeginBlock) (*ReqRes, error)
-	EndBlockAsync(types.RequestEndBlock) (*ReqRes, error)
-	ListSnapshotsAsync(types.RequestListSnapshots) (*ReqRes, error)
-	OfferSnapshotAsync(types.RequestOfferSnapshot) (*ReqRes, error)
-	LoadSnapshotChunkAsync(types.RequestLoadSnapshotChunk) (*ReqRes, error)
-	ApplySnapshotChunkAsync(types.RequestApplySnapshotChunk) (*ReqRes, error)
+	FlushAsync() *ReqRes
+	EchoAsync(msg string) *ReqRes
+	InfoAsync(types.RequestInfo) *ReqRes
+	DeliverTxAsync(types.RequestDeliverTx) *ReqRes
+	CheckTxAsync(types.RequestCheckTx) *ReqRes
+	QueryAsync(types.RequestQuery) *ReqRes
+	CommitAsync() *ReqRes
+	InitChainAsync(types.RequestInitChain) *ReqRes
+	BeginBlockAsync(types.RequestBeginBlock) *ReqRes
+	EndBlockAsync(types.RequestEndBlock) *ReqRes
+	ListSnapshotsAsync(types.RequestListSnapshots) *ReqRes
+	OfferSnapshotAsync(types.RequestOfferSnapshot) *ReqRes
+	LoadSnapshotChunkAsync(types.RequestLoadSnapshotChunk) *ReqRes
+	ApplySnapshotChunkAsync(types.RequestApplySnapshotChunk) *ReqRes
 
 	FlushSync(context.Context) error
 	EchoSync(ctx context.Context, msg string) (*types.ResponseEcho, error)
@@ -77,7 +84,13 @@ func NewClient(addr, transport string, mustConnect bool) (client Client, err err
 
 //----------------------------------------
 
-type Callback func(*types.Request, *types.Response)
+// GlobalCallback is invoked by a Client for every request/response pair it
+// processes. See Client.SetGlobalCallback.
+type GlobalCallback func(*types.Request, *types.Response)
+
+// ResponseCallback is invoked once a single ReqRes has its response set.
+// See ReqRes.SetCallback.
+type ResponseCallback func(*types.Response)
 
 //----------------------------------------
 
@@ -87,8 +100,9 @@ type ReqRes struct {
 	*types.Response // Not set atomically, so be sure to use WaitGroup.
 
 	mtx  tmsync.Mutex
-	done bool                  // Gets set to true once *after* WaitGroup.Done().
-	cb   func(*types.Response) // A single callback that may be set.
+	done bool             // Gets set to true once *after* WaitGroup.Done().
+	cb   ResponseCallback // A single callback that may be set.
+	err  error            // Submission-time error, set before WaitGroup.Done().
 }
 
 func NewReqRes(req *types.Request) *ReqRes {
@@ -99,14 +113,17 @@ func NewReqRes(req *types.Request) *ReqRes {
 
 		done: false,
 		cb:   nil,
+		err:  nil,
 	}
 }
 
-// Sets the callback for this ReqRes atomically.
+// SetCallback sets the callback for this ReqRes atomically.
 // If reqRes is already done, calls cb immediately.
+// The callback still runs when the request failed without ever getting a
+// response: reqRes.Response will be nil and reqRes.Err() will be non-nil.
 // NOTE: reqRes.cb should not change if reqRes.done.
 // NOTE: only one callback is supported.
-func (reqRes *ReqRes) SetCallback(cb func(res *types.Response)) {
+func (reqRes *ReqRes) SetCallback(cb ResponseCallback) {
 	reqRes.mtx.Lock()
 
 	if reqRes.done {
@@ -119,12 +136,25 @@ func (reqRes *ReqRes) SetCallback(cb func(res *types.Response)) {
 	reqRes.mtx.Unlock()
 }
 
-func (reqRes *ReqRes) GetCallback() func(*types.Response) {
+func (reqRes *ReqRes) GetCallback() ResponseCallback {
 	reqRes.mtx.Lock()
 	defer reqRes.mtx.Unlock()
 	return reqRes.cb
 }
 
+// InvokeCallback invokes the per-ReqRes callback, if one is set, with the
+// current response. Exported so client implementations can drive it.
+func (reqRes *ReqRes) InvokeCallback() {
+	reqRes.mtx.Lock()
+	cb := reqRes.cb
+	res := reqRes.Response
+	reqRes.mtx.Unlock()
+
+	if cb != nil {
+		cb(res)
+	}
+}
+
 // NOTE: it should be safe to read reqRes.cb without locks after this.
 func (reqRes *ReqRes) SetDone() {
 	reqRes.mtx.Lock()
@@ -132,6 +162,25 @@ func (reqRes *ReqRes) SetDone() {
 	reqRes.mtx.Unlock()
 }
 
+// Err returns the submission-time error for this ReqRes, if any, eg. the
+// error encountered writing the request to a dead connection. It is safe to
+// call at any time, including from within the ReqRes callback.
+func (reqRes *ReqRes) Err() error {
+	reqRes.mtx.Lock()
+	defer reqRes.mtx.Unlock()
+	return reqRes.err
+}
+
+// SetErr records a submission-time error on this ReqRes.
+// NOTE: must be called before WaitGroup.Done(). Transports (eg. the socket
+// client's write pump) should call this on disconnect to fail queued ReqRes
+// objects; that wiring is not yet present in this tree.
+func (reqRes *ReqRes) SetErr(err error) {
+	reqRes.mtx.Lock()
+	reqRes.err = err
+	reqRes.mtx.Unlock()
+}
+
 func waitGroup1() (wg *sync.WaitGroup) {
 	wg = &sync.WaitGroup{}
 	wg.Add(1)
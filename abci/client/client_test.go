@@ -0,0 +1,67 @@
+package abcicli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/abci/types"
+)
+
+func TestReqResSetCallbackAlreadyDone(t *testing.T) {
+	reqRes := NewReqRes(types.ToRequestFlush())
+	reqRes.Response = types.ToResponseFlush()
+	reqRes.SetDone()
+
+	called := false
+	reqRes.SetCallback(func(res *types.Response) {
+		called = true
+	})
+
+	if !called {
+		t.Fatal("expected callback to run immediately on an already-done ReqRes")
+	}
+}
+
+// TestReqResInvokeCallbackReentrant pins down the InvokeCallback deadlock
+// fixed in 8f920da: the callback must be able to call back into the ReqRes
+// (Err, GetCallback, SetCallback) without blocking on its own mutex.
+func TestReqResInvokeCallbackReentrant(t *testing.T) {
+	reqRes := NewReqRes(types.ToRequestFlush())
+	reqRes.Response = types.ToResponseFlush()
+
+	done := make(chan struct{})
+	reqRes.SetCallback(func(res *types.Response) {
+		_ = reqRes.Err()
+		_ = reqRes.GetCallback()
+		reqRes.SetCallback(func(*types.Response) {})
+		close(done)
+	})
+
+	go reqRes.InvokeCallback()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("InvokeCallback deadlocked on a re-entrant callback")
+	}
+}
+
+func TestReqResErrRoundTrip(t *testing.T) {
+	reqRes := NewReqRes(types.ToRequestFlush())
+
+	if err := reqRes.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil before SetErr", err)
+	}
+
+	wantErr := errors.New("connection closed")
+	reqRes.SetErr(wantErr)
+	if err := reqRes.Err(); err != wantErr {
+		t.Fatalf("Err() = %v, want %v", err, wantErr)
+	}
+
+	reqRes.SetDone()
+	if err := reqRes.Err(); err != wantErr {
+		t.Fatalf("Err() after SetDone = %v, want %v", err, wantErr)
+	}
+}